@@ -0,0 +1,66 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"runtime"
+	"time"
+)
+
+// RecommendationCache is the storage backend behind a CachingRecommender.
+// Implementations may be purely in-process (ttlRecommendationCache,
+// lruRecommendationCache) or backed by an external store shared across
+// recommender replicas (redisRecommendationCache).
+type RecommendationCache interface {
+	// Get returns the cached value for key, or nil if it is absent or expired.
+	Get(key *string) interface{}
+	// Set stores value under key.
+	Set(key *string, value interface{})
+	// Delete removes key from the cache, if present.
+	Delete(key *string)
+	// StartGC starts the backend's background eviction loop, if it has one.
+	// It is a no-op for backends that evict synchronously or delegate
+	// expiry to an external store.
+	StartGC(ttl time.Duration)
+	// StopGC stops the background eviction loop started by StartGC.
+	StopGC()
+}
+
+// ttlRecommendationCache adapts the original time-only TTLCache to the
+// RecommendationCache interface.
+//
+// NOTE: TTLCache's GC loop evicts expired entries internally and has no
+// eviction hook, so unlike lruRecommendationCache.evictOldest, this backend
+// cannot increment metrics.CacheEvictions on TTL expiry. Wiring that up
+// requires a hook in TTLCache itself, which is out of scope here.
+type ttlRecommendationCache struct {
+	*TTLCache
+}
+
+// NewTTLRecommendationCache creates a RecommendationCache backed by an
+// in-process map that only expires entries on TTL. This is the original
+// caching behavior and remains the default.
+func NewTTLRecommendationCache(ttl time.Duration) RecommendationCache {
+	result := &ttlRecommendationCache{TTLCache: NewTTLCache(ttl)}
+	// We need to stop the background GC worker once the cache is no longer
+	// reachable, or it will run forever holding a reference to the TTLCache.
+	runtime.SetFinalizer(result, func(c *ttlRecommendationCache) { c.StopGC() })
+	return result
+}
+
+func (c *ttlRecommendationCache) StartGC(ttl time.Duration) { c.TTLCache.StartCacheGC(ttl) }
+func (c *ttlRecommendationCache) StopGC()                   { c.TTLCache.StopCacheGC() }
@@ -18,16 +18,25 @@ package recommender
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"fmt"
-	"runtime"
+	"strings"
 	"time"
 
 	"k8s.io/autoscaler/vertical-pod-autoscaler/apimock"
+	"k8s.io/autoscaler/vertical-pod-autoscaler/recommender_mock/metrics"
 
 	apiv1 "k8s.io/api/core/v1"
 	hashutil "k8s.io/kubernetes/pkg/util/hash"
+	"k8s.io/klog/v2"
 )
 
+// sizer is implemented by RecommendationCache backends that can report how
+// many entries they currently hold.
+type sizer interface {
+	Len() int
+}
+
 // CachingRecommender provides VPA recommendations for pods.
 // VPA responses are cached.
 type CachingRecommender interface {
@@ -36,49 +45,85 @@ type CachingRecommender interface {
 }
 
 type cachingRecommenderImpl struct {
-	api   apimock.RecommenderAPI
-	cache *TTLCache
+	api              apimock.RecommenderAPI
+	cache            RecommendationCache
+	postProcessors   []RecommendationPostProcessor
+	postProcessorKey string
 }
 
-// NewCachingRecommender creates CachingRecommender with given cache TTL
-func NewCachingRecommender(ttl time.Duration, api apimock.RecommenderAPI) CachingRecommender {
-	ca := NewTTLCache(ttl)
-	ca.StartCacheGC(ttl)
-
-	result := &cachingRecommenderImpl{api: api, cache: ca}
-	// We need to stop background cacheGC worker if cachingRecommenderImpl gets destryed.
-	// If we forget this, background go routine will forever run and hold a reference to TTLCache object.
-	runtime.SetFinalizer(result, stopChacheGC)
-
-	return result
+// NewCachingRecommender creates a CachingRecommender backed by cache, with
+// given cache TTL. The response of every GetRecommendation call is passed
+// through postProcessors, in order, before it is cached and returned.
+// It starts the cache's background GC, if it has one, with cache.StartGC;
+// stopping it is the backend's own responsibility (see e.g.
+// NewTTLRecommendationCache).
+func NewCachingRecommender(ttl time.Duration, api apimock.RecommenderAPI, cache RecommendationCache, postProcessors []RecommendationPostProcessor, postProcessorNames []string) CachingRecommender {
+	cache.StartGC(ttl)
+
+	return &cachingRecommenderImpl{
+		api:              api,
+		cache:            cache,
+		postProcessors:   postProcessors,
+		postProcessorKey: strings.Join(postProcessorNames, ","),
+	}
 }
 
 // Get returns VPA recommendation for the given pod. If recommendation is not in cache, sends request to RecommenderAPI
 func (c *cachingRecommenderImpl) Get(spec *apiv1.PodSpec) (*apimock.Recommendation, error) {
-	cacheKey := getCacheKey(spec)
+	start := time.Now()
+	cacheKey := getCacheKey(spec, c.postProcessorKey)
 	if cacheKey != nil {
 		if cached := c.cache.Get(cacheKey); cached != nil {
+			metrics.CacheHits.Inc()
+			klog.V(4).InfoS("recommendation cache hit", "cacheKey", *cacheKey, "hit", true, "latencyMs", time.Since(start).Milliseconds())
 			return cached.(*apimock.Recommendation), nil
 		}
 	}
+	metrics.CacheMisses.Inc()
 
+	apiStart := time.Now()
 	response, err := c.api.GetRecommendation(spec)
+	metrics.ObserveAPIRequestDuration(apiStart)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching recommendation %v", err)
 	}
+	for _, pp := range c.postProcessors {
+		response, err = pp.Process(spec, response)
+		if err != nil {
+			return nil, fmt.Errorf("error post-processing recommendation %v", err)
+		}
+	}
 	if response != nil && cacheKey != nil {
 		c.cache.Set(cacheKey, response)
+		if s, ok := c.cache.(sizer); ok {
+			metrics.CacheSize.Set(float64(s.Len()))
+		}
+	}
+	if cacheKey != nil {
+		klog.V(4).InfoS("recommendation cache miss", "cacheKey", *cacheKey, "hit", false, "latencyMs", time.Since(start).Milliseconds())
 	}
 	return response, nil
 }
 
-func getCacheKey(spec *apiv1.PodSpec) *string {
-	podTemplateSpecHasher := sha1.New()
-	hashutil.DeepHashObject(podTemplateSpecHasher, *spec)
+// getCacheKey hashes spec together with postProcessorKey, the comma-joined
+// names of the enabled post-processor pipeline, so that two pipelines never
+// share a cache entry. Unless LegacyCacheKey is set, spec is first projected
+// to its CanonicalPodSpec and hashed with SHA-256, so pods that differ only
+// in fields the recommender ignores share a cache entry, and adversarial
+// PodSpecs can't be crafted to collide. LegacyCacheKey restores the old
+// SHA-1-of-the-full-PodSpec behavior for one release during migration.
+func getCacheKey(spec *apiv1.PodSpec, postProcessorKey string) *string {
+	if LegacyCacheKey {
+		podTemplateSpecHasher := sha1.New()
+		hashutil.DeepHashObject(podTemplateSpecHasher, *spec)
+		hashutil.DeepHashObject(podTemplateSpecHasher, postProcessorKey)
+		result := string(podTemplateSpecHasher.Sum(make([]byte, 0)))
+		return &result
+	}
+
+	podTemplateSpecHasher := sha256.New()
+	hashutil.DeepHashObject(podTemplateSpecHasher, CanonicalizePodSpec(spec))
+	hashutil.DeepHashObject(podTemplateSpecHasher, postProcessorKey)
 	result := string(podTemplateSpecHasher.Sum(make([]byte, 0)))
 	return &result
 }
-
-func stopChacheGC(c *cachingRecommenderImpl) {
-	c.cache.StopCacheGC()
-}
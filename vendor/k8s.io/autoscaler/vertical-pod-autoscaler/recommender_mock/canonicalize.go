@@ -0,0 +1,113 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// LegacyCacheKey switches getCacheKey back to hashing the full PodSpec with
+// SHA-1, bound to the --legacy-cache-key flag. It exists for one release to
+// ease migration away from the old cache key and will be removed afterwards.
+var LegacyCacheKey bool
+
+// relevantNodeSelectorKeys lists the NodeSelector entries that change a
+// recommendation and must therefore be part of the cache key. PodSpec has no
+// annotations of its own, so VPA annotations that affect post-processing
+// (e.g. integerCPUAnnotation) are propagated into NodeSelector under these
+// keys before Get is called; everything else in NodeSelector is scheduling
+// metadata the recommender doesn't depend on and is deliberately excluded.
+var relevantNodeSelectorKeys = []string{integerCPUAnnotation}
+
+// CanonicalPodSpec is the projection of a PodSpec that the recommender
+// actually depends on. Two pods that differ only in fields the recommender
+// ignores (nodeName, scheduler-assigned volumes, status-adjacent fields,
+// ...) project to an equal CanonicalPodSpec, and therefore hit the same
+// cache entry. Two pods that differ in a relevant NodeSelector hint (e.g.
+// their integer-cpu opt-in) never do, even if their containers are
+// otherwise identical.
+type CanonicalPodSpec struct {
+	Containers []CanonicalContainer
+	Hints      []CanonicalLabel
+}
+
+// CanonicalLabel is a single key/value pair projected from a pod's
+// NodeSelector, sorted by key so ordering never affects the hash.
+type CanonicalLabel struct {
+	Key   string
+	Value string
+}
+
+// CanonicalContainer is the projection of a container that the recommender
+// depends on.
+type CanonicalContainer struct {
+	Name     string
+	Image    string
+	Requests []CanonicalResource
+	Limits   []CanonicalResource
+	Env      []apiv1.EnvVar
+}
+
+// CanonicalResource is a single resource name/quantity pair, serialized as a
+// string so two equal quantities with different internal representations
+// (e.g. "1000m" vs "1") compare and hash equal.
+type CanonicalResource struct {
+	Name     string
+	Quantity string
+}
+
+// CanonicalizePodSpec projects spec down to the fields the recommender
+// depends on - containers' names, images, resource requests/limits and env,
+// plus the NodeSelector entries named in relevantNodeSelectorKeys - with
+// maps and slices sorted deterministically, so semantically identical
+// pods produce an equal CanonicalPodSpec regardless of field ordering.
+func CanonicalizePodSpec(spec *apiv1.PodSpec) CanonicalPodSpec {
+	containers := make([]CanonicalContainer, len(spec.Containers))
+	for i, c := range spec.Containers {
+		env := append([]apiv1.EnvVar(nil), c.Env...)
+		sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+		containers[i] = CanonicalContainer{
+			Name:     c.Name,
+			Image:    c.Image,
+			Requests: canonicalizeResourceList(c.Resources.Requests),
+			Limits:   canonicalizeResourceList(c.Resources.Limits),
+			Env:      env,
+		}
+	}
+	sort.Slice(containers, func(i, j int) bool { return containers[i].Name < containers[j].Name })
+
+	hints := make([]CanonicalLabel, 0, len(relevantNodeSelectorKeys))
+	for _, key := range relevantNodeSelectorKeys {
+		if value, ok := spec.NodeSelector[key]; ok {
+			hints = append(hints, CanonicalLabel{Key: key, Value: value})
+		}
+	}
+	sort.Slice(hints, func(i, j int) bool { return hints[i].Key < hints[j].Key })
+
+	return CanonicalPodSpec{Containers: containers, Hints: hints}
+}
+
+func canonicalizeResourceList(resources apiv1.ResourceList) []CanonicalResource {
+	result := make([]CanonicalResource, 0, len(resources))
+	for name, quantity := range resources {
+		result = append(result, CanonicalResource{Name: string(name), Quantity: quantity.String()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
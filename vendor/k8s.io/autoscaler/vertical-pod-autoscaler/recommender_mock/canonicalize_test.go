@@ -0,0 +1,59 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+func podSpec(nodeSelector map[string]string) *apiv1.PodSpec {
+	return &apiv1.PodSpec{
+		NodeSelector: nodeSelector,
+		Containers: []apiv1.Container{
+			{Name: "main", Image: "example.com/app:v1"},
+		},
+	}
+}
+
+func TestCanonicalizePodSpecIgnoresIrrelevantFields(t *testing.T) {
+	a := podSpec(nil)
+	a.NodeName = "node-a"
+	b := podSpec(nil)
+	b.NodeName = "node-b"
+
+	if !reflect.DeepEqual(CanonicalizePodSpec(a), CanonicalizePodSpec(b)) {
+		t.Errorf("pods differing only in NodeName should canonicalize equal")
+	}
+	if got, want := *getCacheKey(a, ""), *getCacheKey(b, ""); got != want {
+		t.Errorf("pods differing only in NodeName should hash to the same cache key, got %q != %q", got, want)
+	}
+}
+
+func TestCanonicalizePodSpecIncludesIntegerCPUHint(t *testing.T) {
+	withHint := podSpec(map[string]string{integerCPUAnnotation: "true"})
+	withoutHint := podSpec(nil)
+
+	if reflect.DeepEqual(CanonicalizePodSpec(withHint), CanonicalizePodSpec(withoutHint)) {
+		t.Errorf("pods differing in the integer-cpu opt-in should canonicalize differently")
+	}
+	if got, want := *getCacheKey(withHint, ""), *getCacheKey(withoutHint, ""); got == want {
+		t.Errorf("pods differing in the integer-cpu opt-in should not collide on the same cache key, both got %q", got)
+	}
+}
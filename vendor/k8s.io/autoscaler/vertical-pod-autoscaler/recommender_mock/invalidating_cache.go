@@ -0,0 +1,256 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/vertical-pod-autoscaler/apimock"
+
+	apiv1 "k8s.io/api/core/v1"
+	hashutil "k8s.io/kubernetes/pkg/util/hash"
+
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// InformerMode selects whether an InvalidatingCachingRecommender watches pod
+// templates through the recommender's existing SharedInformerFactory or
+// through an informer of its own.
+type InformerMode int
+
+const (
+	// SharedInformerMode reuses the recommender's existing
+	// SharedInformerFactory. This is the default: it avoids a second watch
+	// on the same resource.
+	SharedInformerMode InformerMode = iota
+	// DedicatedInformerMode starts a private informer, isolating its resync
+	// and watch traffic from the rest of the recommender.
+	DedicatedInformerMode
+)
+
+// refreshInterval is how often the background refresh loop runs, and how far
+// ahead of TTL expiry a cache entry is considered "approaching expiry".
+const refreshInterval = 2 * time.Second
+
+// InvalidatingCachingRecommender is a CachingRecommender that, in addition
+// to TTL expiry, proactively evicts cache entries when the underlying pod's
+// PodTemplate hash changes and refreshes entries approaching TTL expiry in
+// the background, so repeat Get calls for a tracked pod spec are served from
+// cache. A Get whose key is not in the cache (first use, or after an
+// invalidation) still calls the upstream RecommenderAPI synchronously.
+type InvalidatingCachingRecommender interface {
+	CachingRecommender
+	// Invalidate removes the recommendation stored under cacheKey, if any.
+	Invalidate(cacheKey string)
+	// Refresh re-fetches cache entries that are approaching TTL expiry.
+	Refresh(ctx context.Context) error
+}
+
+type invalidatingCachingRecommenderImpl struct {
+	*cachingRecommenderImpl
+	podInformer cache.SharedIndexInformer
+	ttl         time.Duration
+
+	specsMu sync.Mutex
+	specs   map[string]refreshEntry // cacheKey -> most recent Get, for background refresh
+}
+
+type refreshEntry struct {
+	spec      *apiv1.PodSpec
+	fetchedAt time.Time
+}
+
+// NewInvalidatingCachingRecommender creates an InvalidatingCachingRecommender
+// backed by cache, with the given cache TTL and post-processor pipeline.
+// mode selects whether pod templates are watched through sharedInformers
+// (SharedInformerMode, the recommender's existing SharedInformerFactory) or
+// through a dedicated informer built from client (DedicatedInformerMode).
+// The unused factory argument for the chosen mode may be nil. Background
+// watching and refreshing stop when stopCh is closed.
+func NewInvalidatingCachingRecommender(
+	ttl time.Duration,
+	api apimock.RecommenderAPI,
+	recCache RecommendationCache,
+	postProcessors []RecommendationPostProcessor,
+	postProcessorNames []string,
+	mode InformerMode,
+	sharedInformers informers.SharedInformerFactory,
+	client kubernetes.Interface,
+	stopCh <-chan struct{},
+) (InvalidatingCachingRecommender, error) {
+	base, ok := NewCachingRecommender(ttl, api, recCache, postProcessors, postProcessorNames).(*cachingRecommenderImpl)
+	if !ok {
+		return nil, fmt.Errorf("unexpected CachingRecommender implementation")
+	}
+
+	var podInformer cache.SharedIndexInformer
+	switch mode {
+	case SharedInformerMode:
+		if sharedInformers == nil {
+			return nil, fmt.Errorf("shared informer mode requires a non-nil SharedInformerFactory")
+		}
+		podInformer = sharedInformers.Core().V1().Pods().Informer()
+	case DedicatedInformerMode:
+		if client == nil {
+			return nil, fmt.Errorf("dedicated informer mode requires a non-nil client")
+		}
+		podInformer = informers.NewSharedInformerFactory(client, ttl).Core().V1().Pods().Informer()
+	default:
+		return nil, fmt.Errorf("unknown informer mode %v", mode)
+	}
+
+	result := &invalidatingCachingRecommenderImpl{
+		cachingRecommenderImpl: base,
+		podInformer:            podInformer,
+		ttl:                    ttl,
+		specs:                  make(map[string]refreshEntry),
+	}
+
+	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: result.onPodUpdate,
+		DeleteFunc: result.onPodDelete,
+	})
+
+	if mode == DedicatedInformerMode {
+		go podInformer.Run(stopCh)
+	}
+
+	go result.refreshLoop(stopCh)
+
+	return result, nil
+}
+
+// onPodUpdate invalidates the cache entry for a pod's old spec when its
+// PodTemplate hash changes, e.g. after a container image or resource request
+// edit. It is a no-op when the hash is unchanged, which is the common case
+// for status-only updates.
+func (c *invalidatingCachingRecommenderImpl) onPodUpdate(oldObj, newObj interface{}) {
+	oldPod, ok := oldObj.(*apiv1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*apiv1.Pod)
+	if !ok {
+		return
+	}
+	if podTemplateHash(&oldPod.Spec) == podTemplateHash(&newPod.Spec) {
+		return
+	}
+	if cacheKey := getCacheKey(&oldPod.Spec, c.postProcessorKey); cacheKey != nil {
+		c.Invalidate(*cacheKey)
+	}
+}
+
+// onPodDelete invalidates the cache entry for a deleted pod's spec. Without
+// this, a deleted pod's entry would never be removed from specs (nothing
+// calls Get for it again), so refreshLoop would re-fetch it from the
+// RecommenderAPI forever and specs would grow unboundedly as pods churn.
+func (c *invalidatingCachingRecommenderImpl) onPodDelete(obj interface{}) {
+	pod, ok := obj.(*apiv1.Pod)
+	if !ok {
+		tombstone, tsOK := obj.(cache.DeletedFinalStateUnknown)
+		if !tsOK {
+			return
+		}
+		pod, ok = tombstone.Obj.(*apiv1.Pod)
+		if !ok {
+			return
+		}
+	}
+	if cacheKey := getCacheKey(&pod.Spec, c.postProcessorKey); cacheKey != nil {
+		c.Invalidate(*cacheKey)
+	}
+}
+
+// Get returns the VPA recommendation for the given pod, remembering its spec
+// so a background Refresh can re-fetch it once it nears TTL expiry.
+func (c *invalidatingCachingRecommenderImpl) Get(spec *apiv1.PodSpec) (*apimock.Recommendation, error) {
+	if cacheKey := getCacheKey(spec, c.postProcessorKey); cacheKey != nil {
+		c.specsMu.Lock()
+		c.specs[*cacheKey] = refreshEntry{spec: spec, fetchedAt: time.Now()}
+		c.specsMu.Unlock()
+	}
+	return c.cachingRecommenderImpl.Get(spec)
+}
+
+// Invalidate removes the recommendation stored under cacheKey, if any.
+func (c *invalidatingCachingRecommenderImpl) Invalidate(cacheKey string) {
+	c.cache.Delete(&cacheKey)
+	c.specsMu.Lock()
+	delete(c.specs, cacheKey)
+	c.specsMu.Unlock()
+}
+
+// Refresh re-fetches every tracked cache entry that is within refreshInterval
+// of TTL expiry, so that a subsequent Get is served from cache rather than
+// blocking on the RecommenderAPI. It evicts each entry from the backing
+// cache before re-fetching it: Get is cache-aware, so without the eviction
+// it would just return the still-valid cached value instead of ever calling
+// the RecommenderAPI.
+func (c *invalidatingCachingRecommenderImpl) Refresh(ctx context.Context) error {
+	c.specsMu.Lock()
+	type staleEntry struct {
+		cacheKey string
+		spec     *apiv1.PodSpec
+	}
+	stale := make([]staleEntry, 0, len(c.specs))
+	cutoff := time.Now().Add(refreshInterval - c.ttl)
+	for cacheKey, entry := range c.specs {
+		if entry.fetchedAt.Before(cutoff) {
+			stale = append(stale, staleEntry{cacheKey: cacheKey, spec: entry.spec})
+		}
+	}
+	c.specsMu.Unlock()
+
+	for _, entry := range stale {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		c.cache.Delete(&entry.cacheKey)
+		if _, err := c.Get(entry.spec); err != nil {
+			return fmt.Errorf("error refreshing recommendation: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c *invalidatingCachingRecommenderImpl) refreshLoop(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			_ = c.Refresh(context.Background())
+		}
+	}
+}
+
+func podTemplateHash(spec *apiv1.PodSpec) string {
+	hasher := sha1.New()
+	hashutil.DeepHashObject(hasher, *spec)
+	return string(hasher.Sum(make([]byte, 0)))
+}
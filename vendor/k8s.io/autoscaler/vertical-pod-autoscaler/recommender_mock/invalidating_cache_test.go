@@ -0,0 +1,134 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/vertical-pod-autoscaler/apimock"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+// fakeRecommenderAPI counts GetRecommendation calls so tests can assert
+// whether the RecommenderAPI was actually hit.
+type fakeRecommenderAPI struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeRecommenderAPI) GetRecommendation(spec *apiv1.PodSpec) (*apimock.Recommendation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return &apimock.Recommendation{}, nil
+}
+
+func (f *fakeRecommenderAPI) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func newTestInvalidatingCache(api apimock.RecommenderAPI, ttl time.Duration) *invalidatingCachingRecommenderImpl {
+	return &invalidatingCachingRecommenderImpl{
+		cachingRecommenderImpl: &cachingRecommenderImpl{
+			api:   api,
+			cache: NewLRURecommendationCache(0),
+		},
+		ttl:   ttl,
+		specs: make(map[string]refreshEntry),
+	}
+}
+
+func podSpecWithImage(image string) *apiv1.PodSpec {
+	return &apiv1.PodSpec{
+		Containers: []apiv1.Container{{Name: "main", Image: image}},
+	}
+}
+
+func TestInvalidatingCachingRecommenderRefreshRefetchesOnlyEntriesPastCutoff(t *testing.T) {
+	api := &fakeRecommenderAPI{}
+	// refreshInterval is 2s, so with a 10s TTL the cutoff sits 8s in the past:
+	// entries fetched more recently than that are not yet due for refresh.
+	ttl := 10 * time.Second
+	c := newTestInvalidatingCache(api, ttl)
+
+	freshSpec := podSpecWithImage("example.com/fresh:v1")
+	staleSpec := podSpecWithImage("example.com/stale:v1")
+	freshKey := *getCacheKey(freshSpec, "")
+	staleKey := *getCacheKey(staleSpec, "")
+
+	c.specs[freshKey] = refreshEntry{spec: freshSpec, fetchedAt: time.Now()}
+	c.specs[staleKey] = refreshEntry{spec: staleSpec, fetchedAt: time.Now().Add(-9 * time.Second)}
+
+	if err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	if got := api.callCount(); got != 1 {
+		t.Errorf("RecommenderAPI calls = %d, want 1 (only the entry past the refresh cutoff)", got)
+	}
+}
+
+func TestInvalidatingCachingRecommenderInvalidateRemovesCacheAndSpecsEntry(t *testing.T) {
+	c := newTestInvalidatingCache(&fakeRecommenderAPI{}, time.Minute)
+
+	spec := podSpecWithImage("example.com/app:v1")
+	cacheKey := *getCacheKey(spec, "")
+	rec := &apimock.Recommendation{}
+	c.cache.Set(&cacheKey, rec)
+	c.specs[cacheKey] = refreshEntry{spec: spec, fetchedAt: time.Now()}
+
+	c.Invalidate(cacheKey)
+
+	if got := c.cache.Get(&cacheKey); got != nil {
+		t.Errorf("cache.Get after Invalidate = %v, want nil", got)
+	}
+	if _, ok := c.specs[cacheKey]; ok {
+		t.Error("specs entry still present after Invalidate, want removed")
+	}
+}
+
+func TestInvalidatingCachingRecommenderOnPodUpdateEvictsOnlyOnHashChange(t *testing.T) {
+	c := newTestInvalidatingCache(&fakeRecommenderAPI{}, time.Minute)
+
+	oldSpec := podSpecWithImage("example.com/app:v1")
+	cacheKey := *getCacheKey(oldSpec, "")
+	seed := func() {
+		rec := &apimock.Recommendation{}
+		c.cache.Set(&cacheKey, rec)
+		c.specs[cacheKey] = refreshEntry{spec: oldSpec, fetchedAt: time.Now()}
+	}
+
+	seed()
+	unchangedPod := &apiv1.Pod{Spec: *podSpecWithImage("example.com/app:v1")}
+	c.onPodUpdate(&apiv1.Pod{Spec: *oldSpec}, unchangedPod)
+	if got := c.cache.Get(&cacheKey); got == nil {
+		t.Error("onPodUpdate evicted the cache entry for an unchanged PodTemplateHash")
+	}
+
+	seed()
+	changedPod := &apiv1.Pod{Spec: *podSpecWithImage("example.com/app:v2")}
+	c.onPodUpdate(&apiv1.Pod{Spec: *oldSpec}, changedPod)
+	if got := c.cache.Get(&cacheKey); got != nil {
+		t.Error("onPodUpdate did not evict the cache entry for a changed PodTemplateHash")
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/vertical-pod-autoscaler/recommender_mock/metrics"
+)
+
+// lruRecommendationCache is a RecommendationCache bounded by a fixed number
+// of entries, evicting the least recently used entry once full. It is
+// selected with --recommendation-cache-max-entries and bounds memory on
+// large clusters where the unbounded ttlRecommendationCache would OOM.
+type lruRecommendationCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List // front = most recently used
+	elements   map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+// NewLRURecommendationCache creates a RecommendationCache that holds at most
+// maxEntries, evicting by recency once full. A maxEntries of zero or less
+// leaves the cache unbounded.
+func NewLRURecommendationCache(maxEntries int) RecommendationCache {
+	return &lruRecommendationCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		elements:   make(map[string]*list.Element),
+	}
+}
+
+func (c *lruRecommendationCache) Get(key *string) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.elements[*key]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value
+}
+
+func (c *lruRecommendationCache) Set(key *string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.elements[*key]; ok {
+		elem.Value.(*lruEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	elem := c.order.PushFront(&lruEntry{key: *key, value: value})
+	c.elements[*key] = elem
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.evictOldest()
+	}
+}
+
+func (c *lruRecommendationCache) Delete(key *string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.elements[*key]; ok {
+		c.order.Remove(elem)
+		delete(c.elements, *key)
+	}
+}
+
+// evictOldest removes the least recently used entry. The caller must hold c.mu.
+func (c *lruRecommendationCache) evictOldest() {
+	oldest := c.order.Back()
+	if oldest == nil {
+		return
+	}
+	c.order.Remove(oldest)
+	delete(c.elements, oldest.Value.(*lruEntry).key)
+	metrics.CacheEvictions.Inc()
+}
+
+// Len returns the number of entries currently in the cache.
+func (c *lruRecommendationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// StartGC and StopGC are no-ops: entries are evicted synchronously on Set
+// once the cache is full, so there is no background worker to run.
+func (c *lruRecommendationCache) StartGC(ttl time.Duration) {}
+func (c *lruRecommendationCache) StopGC()                   {}
@@ -0,0 +1,56 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import "testing"
+
+func key(s string) *string { return &s }
+
+func TestLRURecommendationCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRURecommendationCache(2)
+
+	c.Set(key("a"), "a-value")
+	c.Set(key("b"), "b-value")
+	if got := c.Get(key("a")); got != "a-value" {
+		t.Fatalf("Get(a) = %v, want a-value", got)
+	}
+
+	// "a" was just touched, so "b" is now the least recently used entry and
+	// should be evicted to make room for "c".
+	c.Set(key("c"), "c-value")
+
+	if got := c.Get(key("b")); got != nil {
+		t.Errorf("Get(b) = %v, want nil (b should have been evicted)", got)
+	}
+	if got := c.Get(key("a")); got != "a-value" {
+		t.Errorf("Get(a) = %v, want a-value", got)
+	}
+	if got := c.Get(key("c")); got != "c-value" {
+		t.Errorf("Get(c) = %v, want c-value", got)
+	}
+}
+
+func TestLRURecommendationCacheDelete(t *testing.T) {
+	c := NewLRURecommendationCache(2)
+	c.Set(key("a"), "a-value")
+
+	c.Delete(key("a"))
+
+	if got := c.Get(key("a")); got != nil {
+		t.Errorf("Get(a) after Delete = %v, want nil", got)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exports Prometheus metrics for the recommendation cache
+// and its upstream RecommenderAPI calls, so cache sizing and TTL tuning can
+// be data-driven instead of guesswork.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// CacheHits counts recommendation cache hits.
+	CacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "vpa_recommendation_cache_hits_total",
+		Help: "Number of recommendation cache hits.",
+	})
+	// CacheMisses counts recommendation cache misses.
+	CacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "vpa_recommendation_cache_misses_total",
+		Help: "Number of recommendation cache misses.",
+	})
+	// CacheEvictions counts entries evicted from the recommendation cache.
+	// Only backends that can observe their own evictions (currently the LRU
+	// backend) increment this; see lruRecommendationCache.evictOldest.
+	CacheEvictions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "vpa_recommendation_cache_evictions_total",
+		Help: "Number of recommendation cache entries evicted.",
+	})
+	// CacheSize reports the current number of entries in the recommendation
+	// cache, for backends that can report their size.
+	CacheSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "vpa_recommendation_cache_size",
+		Help: "Current number of entries in the recommendation cache.",
+	})
+	// APIRequestDuration observes the latency of RecommenderAPI.GetRecommendation calls.
+	APIRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "vpa_recommender",
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of RecommenderAPI.GetRecommendation calls.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// Register registers all recommender cache and API metrics with r.
+func Register(r prometheus.Registerer) {
+	r.MustRegister(CacheHits, CacheMisses, CacheEvictions, CacheSize, APIRequestDuration)
+}
+
+// ObserveAPIRequestDuration records how long an upstream GetRecommendation
+// call took, given its start time.
+func ObserveAPIRequestDuration(start time.Time) {
+	APIRequestDuration.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"k8s.io/autoscaler/vertical-pod-autoscaler/apimock"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// integerCPUAnnotation, when set to "true" on a pod's NodeSelector by the
+// owning VPA, opts a pod into the integer-cpu post-processor. PodSpec has no
+// annotations of its own, so the VPA controller propagates its annotation
+// into the built PodSpec under this well-known key before calling Get.
+const integerCPUAnnotation = "recommender.k8s.io/integer-cpu"
+
+// RecommendationPostProcessor transforms a recommendation after it has been
+// fetched from the RecommenderAPI and before it is cached or returned to the
+// caller.
+type RecommendationPostProcessor interface {
+	// Process returns a (possibly new) recommendation derived from rec. It
+	// must not mutate rec in place.
+	Process(spec *apiv1.PodSpec, rec *apimock.Recommendation) (*apimock.Recommendation, error)
+}
+
+// PostProcessorFactory builds an ordered post-processor pipeline from the
+// names given on the --recommendation-post-processors flag.
+type PostProcessorFactory struct {
+	builders map[string]func() RecommendationPostProcessor
+}
+
+// NewPostProcessorFactory creates a PostProcessorFactory with all built-in
+// post-processors registered.
+func NewPostProcessorFactory() *PostProcessorFactory {
+	return &PostProcessorFactory{
+		builders: map[string]func() RecommendationPostProcessor{
+			"integer-cpu": func() RecommendationPostProcessor { return &integerCPUPostProcessor{} },
+			"capping":     func() RecommendationPostProcessor { return &cappingPostProcessor{} },
+		},
+	}
+}
+
+// Build parses a comma-separated --recommendation-post-processors flag value
+// into an ordered pipeline. The capping post-processor is always appended
+// last, whether or not it was named explicitly, so recommendations never
+// leave the pipeline outside their container's min/max policy. The returned
+// names are normalized (trimmed, capping included) and are suitable for use
+// in the recommendation cache key, since different pipelines must not share
+// cache entries.
+func (f *PostProcessorFactory) Build(names string) (pipeline []RecommendationPostProcessor, enabledNames []string, err error) {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+			continue
+		case "capping":
+			continue // always appended last, below
+		default:
+			build, ok := f.builders[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown recommendation post-processor %q", name)
+			}
+			pipeline = append(pipeline, build())
+			enabledNames = append(enabledNames, name)
+		}
+	}
+	pipeline = append(pipeline, f.builders["capping"]())
+	enabledNames = append(enabledNames, "capping")
+	return pipeline, enabledNames, nil
+}
+
+// integerCPUPostProcessor rounds CPU recommendations up to whole cores for
+// pods whose owning VPA opted in via integerCPUAnnotation.
+type integerCPUPostProcessor struct{}
+
+func (p *integerCPUPostProcessor) Process(spec *apiv1.PodSpec, rec *apimock.Recommendation) (*apimock.Recommendation, error) {
+	if rec == nil || spec.NodeSelector[integerCPUAnnotation] != "true" {
+		return rec, nil
+	}
+	result := *rec
+	result.ContainerRecommendations = make([]apimock.ContainerRecommendation, len(rec.ContainerRecommendations))
+	for i, cr := range rec.ContainerRecommendations {
+		cr.Target = roundUpCPU(cr.Target)
+		cr.LowerBound = roundUpCPU(cr.LowerBound)
+		cr.UpperBound = roundUpCPU(cr.UpperBound)
+		result.ContainerRecommendations[i] = cr
+	}
+	return &result, nil
+}
+
+func roundUpCPU(resources apiv1.ResourceList) apiv1.ResourceList {
+	cpu, ok := resources[apiv1.ResourceCPU]
+	if !ok {
+		return resources
+	}
+	result := resources.DeepCopy()
+	cores := math.Ceil(cpu.AsApproximateFloat64())
+	result[apiv1.ResourceCPU] = *resource.NewQuantity(int64(cores), resource.DecimalSI)
+	return result
+}
+
+// cappingPostProcessor clamps recommendations to each container's min/max
+// policy, taken from the requests (floor) and limits (ceiling) already
+// declared on the PodSpec. It is always the last stage of the pipeline.
+type cappingPostProcessor struct{}
+
+func (p *cappingPostProcessor) Process(spec *apiv1.PodSpec, rec *apimock.Recommendation) (*apimock.Recommendation, error) {
+	if rec == nil {
+		return rec, nil
+	}
+	policies := make(map[string]apiv1.ResourceRequirements, len(spec.Containers))
+	for _, c := range spec.Containers {
+		policies[c.Name] = c.Resources
+	}
+	result := *rec
+	result.ContainerRecommendations = make([]apimock.ContainerRecommendation, len(rec.ContainerRecommendations))
+	for i, cr := range rec.ContainerRecommendations {
+		if policy, ok := policies[cr.ContainerName]; ok {
+			cr.Target = clamp(cr.Target, policy.Requests, policy.Limits)
+			cr.LowerBound = clamp(cr.LowerBound, policy.Requests, policy.Limits)
+			cr.UpperBound = clamp(cr.UpperBound, policy.Requests, policy.Limits)
+		}
+		result.ContainerRecommendations[i] = cr
+	}
+	return &result, nil
+}
+
+func clamp(target, min, max apiv1.ResourceList) apiv1.ResourceList {
+	result := target.DeepCopy()
+	for name, quantity := range result {
+		if minQuantity, ok := min[name]; ok && quantity.Cmp(minQuantity) < 0 {
+			quantity = minQuantity
+		}
+		if maxQuantity, ok := max[name]; ok && quantity.Cmp(maxQuantity) > 0 {
+			quantity = maxQuantity
+		}
+		result[name] = quantity
+	}
+	return result
+}
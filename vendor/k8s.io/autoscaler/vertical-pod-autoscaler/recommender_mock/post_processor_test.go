@@ -0,0 +1,60 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostProcessorFactoryBuildOrdersAndAppendsCappingLast(t *testing.T) {
+	f := NewPostProcessorFactory()
+
+	pipeline, names, err := f.Build("integer-cpu")
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if want := []string{"integer-cpu", "capping"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	if len(pipeline) != 2 {
+		t.Fatalf("pipeline length = %d, want 2", len(pipeline))
+	}
+	if _, ok := pipeline[1].(*cappingPostProcessor); !ok {
+		t.Errorf("last stage = %T, want *cappingPostProcessor", pipeline[1])
+	}
+}
+
+func TestPostProcessorFactoryBuildDedupesExplicitCapping(t *testing.T) {
+	f := NewPostProcessorFactory()
+
+	_, names, err := f.Build("capping,integer-cpu,capping")
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if want := []string{"integer-cpu", "capping"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v (capping named explicitly should still only appear once, as the last stage)", names, want)
+	}
+}
+
+func TestPostProcessorFactoryBuildRejectsUnknownName(t *testing.T) {
+	f := NewPostProcessorFactory()
+
+	if _, _, err := f.Build("not-a-real-post-processor"); err == nil {
+		t.Error("Build should reject an unknown post-processor name")
+	}
+}
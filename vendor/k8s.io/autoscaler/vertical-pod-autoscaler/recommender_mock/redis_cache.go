@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package recommender
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"k8s.io/autoscaler/vertical-pod-autoscaler/apimock"
+	"k8s.io/klog/v2"
+)
+
+// redisRecommendationCache is a RecommendationCache backed by Redis, keyed
+// by the same pod-spec hash as the other backends. Unlike the in-process
+// backends, it is shared by every recommender replica (and the standby
+// leader), so a cache warmed by one replica survives a restart or
+// leader-election failover on any other.
+type redisRecommendationCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRedisRecommendationCache creates a RecommendationCache backed by the
+// given Redis client. Entries expire via Redis's own TTL rather than a local
+// GC loop, so StartGC/StopGC are no-ops.
+func NewRedisRecommendationCache(client *redis.Client, ttl time.Duration) RecommendationCache {
+	return &redisRecommendationCache{client: client, ttl: ttl}
+}
+
+func (c *redisRecommendationCache) Get(key *string) interface{} {
+	data, err := c.client.Get(context.Background(), *key).Bytes()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		klog.V(4).InfoS("error reading recommendation cache from redis", "cacheKey", *key, "err", err)
+		return nil
+	}
+	var rec apimock.Recommendation
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		klog.V(4).InfoS("error decoding cached recommendation", "cacheKey", *key, "err", err)
+		return nil
+	}
+	return &rec
+}
+
+func (c *redisRecommendationCache) Set(key *string, value interface{}) {
+	rec, ok := value.(*apimock.Recommendation)
+	if !ok {
+		klog.V(4).InfoS("refusing to cache non-Recommendation value in redis", "cacheKey", *key)
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		klog.V(4).InfoS("error encoding recommendation for redis cache", "cacheKey", *key, "err", err)
+		return
+	}
+	if err := c.client.Set(context.Background(), *key, buf.Bytes(), c.ttl).Err(); err != nil {
+		klog.V(4).InfoS("error writing recommendation cache to redis", "cacheKey", *key, "err", err)
+	}
+}
+
+func (c *redisRecommendationCache) Delete(key *string) {
+	if err := c.client.Del(context.Background(), *key).Err(); err != nil {
+		klog.V(4).InfoS("error deleting recommendation cache entry from redis", "cacheKey", *key, "err", err)
+	}
+}
+
+// StartGC and StopGC are no-ops: Redis expires entries itself via the TTL
+// passed to Set.
+func (c *redisRecommendationCache) StartGC(ttl time.Duration) {}
+func (c *redisRecommendationCache) StopGC()                   {}